@@ -0,0 +1,93 @@
+package chain
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// HandlerC is the context-aware handler interface used by xhandler and
+// similar packages: ServeHTTPC(ctx, w, r) instead of our ServeHTTPContext.
+// BridgeC lets middleware written against that interface be dropped into a
+// Chain unchanged.
+type HandlerC interface {
+	ServeHTTPC(context.Context, http.ResponseWriter, *http.Request)
+}
+
+// HandlerFuncC adapts a func with HandlerC's signature to a HandlerC.
+type HandlerFuncC func(context.Context, http.ResponseWriter, *http.Request)
+
+// ServeHTTPC calls f(ctx, w, r)
+func (f HandlerFuncC) ServeHTTPC(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	f(ctx, w, r)
+}
+
+type handlerCAdapter struct {
+	Handler
+}
+
+func (h handlerCAdapter) ServeHTTPC(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	h.Handler.ServeHTTPContext(ctx, w, r)
+}
+
+type handlerAdapterC struct {
+	HandlerC
+}
+
+func (h handlerAdapterC) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	h.HandlerC.ServeHTTPC(ctx, w, r)
+}
+
+// BridgeC takes a middleware function written against HandlerC (as used by
+// xhandler, apollo, and similar packages) and returns a chain.Handler wrap.
+func BridgeC(h func(HandlerC) HandlerC) func(Handler) Handler {
+	return func(n Handler) Handler {
+		return handlerAdapterC{h(handlerCAdapter{n})}
+	}
+}
+
+// BridgeStd takes a standard func(http.Handler) http.Handler middleware and
+// returns a chain.Handler wrap, like Bridge, except that it additionally
+// propagates the Chain's context into the *http.Request seen by the
+// middleware (via r.WithContext), and reads the context back out of the
+// *http.Request the middleware eventually passes on, so any replacement it
+// made (e.g. via its own r.WithContext call) reaches the downstream Handler.
+// This lets middleware that reads or derives from r.Context(), rather than
+// the non-context-aware http.Handler signature Bridge targets, participate
+// correctly in a Chain.
+func BridgeStd(h func(http.Handler) http.Handler) func(Handler) Handler {
+	return func(n Handler) Handler {
+		return HandlerFunc(
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+				inner := http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						n.ServeHTTPContext(r.Context(), w, r)
+					},
+				)
+				h(inner).ServeHTTP(w, r.WithContext(ctx))
+			},
+		)
+	}
+}
+
+// FromStdHandler adapts an http.Handler to a chain.Handler. The context
+// passed to ServeHTTPContext is attached to the *http.Request (via
+// WithContext) before h is invoked, so h can recover it through r.Context()
+// if it is context-aware itself.
+func FromStdHandler(h http.Handler) Handler {
+	return HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r.WithContext(ctx))
+		},
+	)
+}
+
+// ToStdHandler adapts a chain.Handler to an http.Handler, using r.Context()
+// as the context passed to ServeHTTPContext.
+func ToStdHandler(h Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTPContext(r.Context(), w, r)
+		},
+	)
+}
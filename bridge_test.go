@@ -0,0 +1,99 @@
+package chain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+type bridgeTestCtxKey int
+
+const bridgeTestKey bridgeTestCtxKey = 0
+
+func TestBridgeStdPropagatesContextBothWays(t *testing.T) {
+	std := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if v := r.Context().Value(bridgeTestKey); v != "in" {
+					t.Fatalf("BridgeStd did not propagate the Chain's context into the *http.Request; got %v", v)
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), bridgeTestKey, "out")))
+			},
+		)
+	}
+
+	var got interface{}
+	h := BridgeStd(std)(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			got = ctx.Value(bridgeTestKey)
+		},
+	))
+
+	ctx := context.WithValue(context.Background(), bridgeTestKey, "in")
+	h.ServeHTTPContext(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got != "out" {
+		t.Fatalf("downstream Handler saw ctx value %v, want %q (BridgeStd should copy the middleware's context replacement back out)", got, "out")
+	}
+}
+
+func TestBridgeC(t *testing.T) {
+	var got string
+	mw := func(next HandlerC) HandlerC {
+		return HandlerFuncC(
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+				got += "mw;"
+				next.ServeHTTPC(ctx, w, r)
+			},
+		)
+	}
+
+	h := BridgeC(mw)(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			got += "handler"
+		},
+	))
+
+	h.ServeHTTPContext(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if want := "mw;handler"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFromStdHandlerAttachesContextToRequest(t *testing.T) {
+	std := http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Context().Value(bridgeTestKey).(string)))
+		},
+	)
+
+	h := FromStdHandler(std)
+	ctx := context.WithValue(context.Background(), bridgeTestKey, "value")
+	rec := httptest.NewRecorder()
+	h.ServeHTTPContext(ctx, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if want := "value"; rec.Body.String() != want {
+		t.Fatalf("FromStdHandler did not attach ctx to the *http.Request: body = %q, want %q", rec.Body.String(), want)
+	}
+}
+
+func TestToStdHandlerPassesRequestContextThrough(t *testing.T) {
+	h := ToStdHandler(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(ctx.Value(bridgeTestKey).(string)))
+		},
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(
+		context.WithValue(context.Background(), bridgeTestKey, "std"),
+	)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if want := "std"; rec.Body.String() != want {
+		t.Fatalf("ToStdHandler did not pass r.Context() through: body = %q, want %q", rec.Body.String(), want)
+	}
+}
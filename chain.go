@@ -5,6 +5,7 @@ package chain
 
 import (
 	"net/http"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -81,6 +82,41 @@ func (c Chain) EndFn(h HandlerFunc) http.Handler {
 	return c.End(h)
 }
 
+// Merge returns a new Chain whose middleware is the receiver's wraps
+// followed by the wraps of each of others, in order. The receiver's ctx is
+// kept. Merge is useful for building reusable base Chains (e.g. a "common"
+// Chain shared by several routes) and combining them without re-listing
+// their wraps by hand.
+func (c Chain) Merge(others ...Chain) Chain {
+	m := make([]func(Handler) Handler, len(c.m))
+	copy(m, c.m)
+
+	for _, o := range others {
+		m = append(m, o.m...)
+	}
+
+	return Chain{ctx: c.ctx, m: m}
+}
+
+// Clone returns an independent copy of c. Because Append grows c.m with the
+// builtin append, two Chains produced from the same base can otherwise end
+// up sharing the same backing array, so that appending to one silently
+// corrupts the other once its capacity allows an in-place append. Clone
+// avoids that by allocating a fresh slice up front.
+func (c Chain) Clone() Chain {
+	m := make([]func(Handler) Handler, len(c.m))
+	copy(m, c.m)
+
+	return Chain{ctx: c.ctx, m: m}
+}
+
+// WithTimeout appends a TimeoutHandler(d) wrap to the Chain and returns the
+// new Chain. It is a convenience for the common case of giving every request
+// passing through the Chain a bounded lifetime.
+func (c Chain) WithTimeout(d time.Duration) Chain {
+	return c.Append(TimeoutHandler(d))
+}
+
 // Bridge takes a standard http.Handler wrapping function and returns a
 // chain.Handler wrap.  This is useful for making non-context aware
 // http.Handler wraps compatible with the rest of a Chain.
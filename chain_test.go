@@ -0,0 +1,71 @@
+package chain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// mark returns a Handler wrap that appends id to *out before calling the
+// next Handler, so tests can observe both whether a wrap ran and the order
+// in which wraps ran.
+func mark(id string, out *[]string) func(Handler) Handler {
+	return func(next Handler) Handler {
+		return HandlerFunc(
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+				*out = append(*out, id)
+				next.ServeHTTPContext(ctx, w, r)
+			},
+		)
+	}
+}
+
+func TestMergeOrderAndIndependence(t *testing.T) {
+	var got []string
+	base := New(context.Background(), mark("base", &got))
+	extra := New(context.Background(), mark("extra", &got))
+
+	merged := base.Merge(extra)
+
+	final := HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {})
+	merged.End(final).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if want := []string{"base", "extra"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("merged chain ran %v, want %v", got, want)
+	}
+
+	// Appending to extra after the Merge must not retroactively affect the
+	// already-merged Chain, since Merge copies rather than aliases.
+	got = nil
+	extra = extra.Append(mark("extra-late", &got))
+	merged.End(final).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if want := []string{"base", "extra"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("merged chain ran %v after extending extra, want unaffected %v", got, want)
+	}
+}
+
+func TestCloneIndependentExtension(t *testing.T) {
+	var got []string
+	base := New(context.Background(), mark("base", &got))
+	clone := base.Clone()
+
+	clone = clone.Append(mark("clone-only", &got))
+	base = base.Append(mark("base-only", &got))
+
+	final := HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {})
+
+	got = nil
+	clone.End(final).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if want := []string{"base", "clone-only"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("clone ran %v, want %v", got, want)
+	}
+
+	got = nil
+	base.End(final).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if want := []string{"base", "base-only"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("base ran %v, want %v (clone-only wrap leaked into base)", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package chain
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// CloseHandler returns a Handler wrap that derives a cancellable context from
+// the one it is passed, and cancels it as soon as the client disconnects.
+// This lets downstream handlers abort expensive work (database calls, RPCs)
+// instead of running it to completion for a request nobody is waiting on
+// anymore. If the http.ResponseWriter implements http.CloseNotifier that is
+// used to detect the disconnect; otherwise r.Context().Done() is used as a
+// fallback. Either way the watching goroutine exits via done as soon as the
+// wrapped Handler returns, so it never leaks.
+func CloseHandler(h Handler) Handler {
+	return HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			done := make(chan struct{})
+			defer close(done)
+
+			if cn, ok := w.(http.CloseNotifier); ok {
+				closed := cn.CloseNotify()
+				go func() {
+					select {
+					case <-closed:
+						cancel()
+					case <-done:
+					}
+				}()
+			} else {
+				go func() {
+					select {
+					case <-r.Context().Done():
+						cancel()
+					case <-done:
+					}
+				}()
+			}
+
+			h.ServeHTTPContext(ctx, w, r)
+		},
+	)
+}
+
+// TimeoutHandler returns a Handler wrap that bounds the downstream Handler's
+// context to d, via context.WithTimeout. The derived context is cancelled
+// either when the deadline elapses or when the Handler returns, whichever
+// happens first.
+func TimeoutHandler(d time.Duration) func(Handler) Handler {
+	return func(h Handler) Handler {
+		return HandlerFunc(
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+				ctx, cancel := context.WithTimeout(ctx, d)
+				defer cancel()
+
+				h.ServeHTTPContext(ctx, w, r)
+			},
+		)
+	}
+}
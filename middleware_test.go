@@ -0,0 +1,124 @@
+package chain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type closeNotifierRecorder struct {
+	*httptest.ResponseRecorder
+	closeCh chan bool
+}
+
+func (w *closeNotifierRecorder) CloseNotify() <-chan bool {
+	return w.closeCh
+}
+
+func TestCloseHandlerCancelsOnCloseNotify(t *testing.T) {
+	w := &closeNotifierRecorder{ResponseRecorder: httptest.NewRecorder(), closeCh: make(chan bool, 1)}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	canceled := make(chan struct{})
+	h := CloseHandler(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			w.(*closeNotifierRecorder).closeCh <- true
+			select {
+			case <-ctx.Done():
+				close(canceled)
+			case <-time.After(time.Second):
+			}
+		},
+	))
+
+	h.ServeHTTPContext(context.Background(), w, req)
+
+	select {
+	case <-canceled:
+	default:
+		t.Fatal("context was not canceled after http.CloseNotifier fired")
+	}
+}
+
+func TestCloseHandlerCancelsOnRequestContextDone(t *testing.T) {
+	w := httptest.NewRecorder()
+	reqCtx, cancelReq := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	h := CloseHandler(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			close(started)
+			select {
+			case <-ctx.Done():
+				close(canceled)
+			case <-time.After(time.Second):
+			}
+		},
+	))
+
+	go func() {
+		<-started
+		cancelReq()
+	}()
+
+	h.ServeHTTPContext(context.Background(), w, req)
+
+	select {
+	case <-canceled:
+	default:
+		t.Fatal("context was not canceled after r.Context() was done")
+	}
+}
+
+func TestCloseHandlerDoesNotLeakItsWatcherGoroutine(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	h := CloseHandler(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {},
+	))
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		h.ServeHTTPContext(context.Background(), w, req)
+	}
+
+	// Give the watcher goroutines a moment to exit via their done channel.
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("goroutine count grew from %d to %d after 10 requests; CloseHandler may be leaking its watcher goroutine", before, after)
+	}
+}
+
+func TestTimeoutHandlerCancelsContextOnDeadline(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	canceled := make(chan struct{})
+	h := TimeoutHandler(10 * time.Millisecond)(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-ctx.Done():
+				close(canceled)
+			case <-time.After(time.Second):
+			}
+		},
+	))
+
+	h.ServeHTTPContext(context.Background(), w, req)
+
+	select {
+	case <-canceled:
+	default:
+		t.Fatal("context was not canceled once TimeoutHandler's deadline elapsed")
+	}
+}
@@ -0,0 +1,232 @@
+// Package mux provides a lightweight, trie-based HTTP router whose route
+// handlers are chain.Handler and whose route groups accept a chain.Chain, so
+// that ordinary Chain middleware can be scoped to a subtree of routes.
+package mux
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/Obighbyd/chain"
+)
+
+// node is one segment of the routing trie. A segment is either a literal
+// (stored in children, keyed by the literal text) or a single named
+// parameter (param, matching any non-empty segment and binding it to
+// paramName). mount, if set, matches this node and the rest of the path
+// beneath it, however deep, handing the whole remainder over to a mounted
+// http.Handler instead of continuing to walk the trie.
+type node struct {
+	children  map[string]*node
+	param     *node
+	paramName string
+	routes    map[string]*route
+	mount     *route
+}
+
+func newNode() *node {
+	return &node{children: make(map[string]*node)}
+}
+
+func (n *node) child(segment string) *node {
+	if strings.HasPrefix(segment, ":") {
+		if n.param == nil {
+			n.param = newNode()
+			n.param.paramName = segment[1:]
+		}
+		return n.param
+	}
+
+	c, ok := n.children[segment]
+	if !ok {
+		c = newNode()
+		n.children[segment] = c
+	}
+	return c
+}
+
+// route is what gets attached to a node for a given HTTP method: the
+// middleware inherited from the Router (and any enclosing Group) at the time
+// the route was registered, plus the route's own Handler.
+type route struct {
+	mw []func(chain.Handler) chain.Handler
+	h  chain.Handler
+}
+
+// Router is a mountable, trie-based router. Its zero value is not usable;
+// create one with New.
+type Router struct {
+	mw   []func(chain.Handler) chain.Handler
+	root *node
+}
+
+// New returns an empty Router.
+func New() *Router {
+	return &Router{root: newNode()}
+}
+
+// Use appends middleware that applies to every route registered on r (and
+// its Groups) from this point on.
+func (r *Router) Use(mw ...func(chain.Handler) chain.Handler) {
+	r.mw = append(r.mw, mw...)
+}
+
+// Group creates an inline Router sharing r's routing trie and inherited
+// middleware, and runs fn against it. Middleware added inside fn (via Use)
+// only applies to routes registered inside fn, letting a subset of routes
+// opt into extra middleware without moving them under a new path prefix.
+func (r *Router) Group(fn func(r *Router)) {
+	sub := &Router{root: r.root, mw: append([]func(chain.Handler) chain.Handler{}, r.mw...)}
+	fn(sub)
+}
+
+// Route creates a Router scoped under pattern and runs fn against it. Unlike
+// Group, routes registered inside fn are mounted under pattern, so nested
+// Routes build up a path instead of just a middleware scope.
+func (r *Router) Route(pattern string, fn func(r *Router)) {
+	n := r.nodeFor(pattern)
+	sub := &Router{root: n, mw: append([]func(chain.Handler) chain.Handler{}, r.mw...)}
+	fn(sub)
+}
+
+// Mount attaches sub under pattern so that it handles the matched pattern
+// and everything beneath it, with the matched prefix stripped from the
+// request URL before sub sees it, similar to http.StripPrefix. sub is plain
+// http.Handler, so any handler — including another Router or a
+// chain.Chain-terminated one — can be mounted. Middleware inherited from r
+// (and any enclosing Group) applies to the mounted subtree exactly as it
+// does to routes registered with Get/Post/etc.
+func (r *Router) Mount(pattern string, sub http.Handler) {
+	prefix := strings.TrimSuffix(pattern, "/")
+	n := r.nodeFor(pattern)
+	h := chain.HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+			req2 := new(http.Request)
+			*req2 = *req
+			req2.URL = new(url.URL)
+			*req2.URL = *req.URL
+			req2.URL.Path = strings.TrimPrefix(req.URL.Path, prefix)
+			if req2.URL.Path == "" {
+				req2.URL.Path = "/"
+			}
+			sub.ServeHTTP(w, req2)
+		},
+	)
+
+	n.mount = &route{
+		mw: append([]func(chain.Handler) chain.Handler{}, r.mw...),
+		h:  h,
+	}
+}
+
+// Get registers pattern to be handled by h for GET requests.
+func (r *Router) Get(pattern string, h chain.Handler) { r.handle(http.MethodGet, pattern, h) }
+
+// Post registers pattern to be handled by h for POST requests.
+func (r *Router) Post(pattern string, h chain.Handler) { r.handle(http.MethodPost, pattern, h) }
+
+// Put registers pattern to be handled by h for PUT requests.
+func (r *Router) Put(pattern string, h chain.Handler) { r.handle(http.MethodPut, pattern, h) }
+
+// Delete registers pattern to be handled by h for DELETE requests.
+func (r *Router) Delete(pattern string, h chain.Handler) { r.handle(http.MethodDelete, pattern, h) }
+
+// Patch registers pattern to be handled by h for PATCH requests.
+func (r *Router) Patch(pattern string, h chain.Handler) { r.handle(http.MethodPatch, pattern, h) }
+
+func (r *Router) handle(method, pattern string, h chain.Handler) {
+	n := r.nodeFor(pattern)
+	if n.routes == nil {
+		n.routes = make(map[string]*route)
+	}
+	n.routes[method] = &route{
+		mw: append([]func(chain.Handler) chain.Handler{}, r.mw...),
+		h:  h,
+	}
+}
+
+func (r *Router) nodeFor(pattern string) *node {
+	n := r.root
+	for _, seg := range splitPath(pattern) {
+		n = n.child(seg)
+	}
+	return n
+}
+
+func splitPath(pattern string) []string {
+	pattern = strings.Trim(pattern, "/")
+	if pattern == "" {
+		return nil
+	}
+	return strings.Split(pattern, "/")
+}
+
+type paramsCtxKey int
+
+const paramsKey paramsCtxKey = 0
+
+// Param returns the value bound to name by the route pattern that matched
+// the request ctx was derived from, e.g. Param(ctx, "id") for a route
+// registered as "/users/:id". It returns "" if name was not bound.
+func Param(ctx context.Context, name string) string {
+	params, _ := ctx.Value(paramsKey).(map[string]string)
+	return params[name]
+}
+
+func (r *Router) match(method, path string) (*route, map[string]string) {
+	n := r.root
+	var params map[string]string
+
+	for _, seg := range splitPath(path) {
+		if n.mount != nil {
+			return n.mount, params
+		}
+		if c, ok := n.children[seg]; ok {
+			n = c
+			continue
+		}
+		if n.param != nil && seg != "" {
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[n.param.paramName] = seg
+			n = n.param
+			continue
+		}
+		return nil, nil
+	}
+
+	if n.mount != nil {
+		return n.mount, params
+	}
+
+	if n.routes == nil {
+		return nil, nil
+	}
+	return n.routes[method], params
+}
+
+// ServeHTTPContext implements chain.Handler, so a Router can itself be
+// mounted inside a Chain (e.g. via Chain.End) or nested with Mount.
+func (r *Router) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	rt, params := r.match(req.Method, req.URL.Path)
+	if rt == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	if params != nil {
+		ctx = context.WithValue(ctx, paramsKey, params)
+	}
+
+	chain.New(ctx, rt.mw...).End(rt.h).ServeHTTP(w, req)
+}
+
+// ServeHTTP implements http.Handler using req.Context() as the root context,
+// so a Router can be passed directly to http.ListenAndServe.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.ServeHTTPContext(req.Context(), w, req)
+}
@@ -0,0 +1,129 @@
+package mux
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/Obighbyd/chain"
+)
+
+// markMW returns a chain.Handler wrap that appends id to *out before calling
+// the next Handler, letting tests observe both whether a wrap ran and the
+// order in which wraps ran.
+func markMW(id string, out *[]string) func(chain.Handler) chain.Handler {
+	return func(next chain.Handler) chain.Handler {
+		return chain.HandlerFunc(
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+				*out = append(*out, id)
+				next.ServeHTTPContext(ctx, w, r)
+			},
+		)
+	}
+}
+
+// markHandler returns a terminal chain.Handler that appends id to *out.
+func markHandler(id string, out *[]string) chain.Handler {
+	return chain.HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			*out = append(*out, id)
+		},
+	)
+}
+
+func TestLiteralTakesPrecedenceOverParam(t *testing.T) {
+	var got []string
+	r := New()
+	r.Get("/users/:id", markHandler("param", &got))
+	r.Get("/users/me", markHandler("literal", &got))
+
+	got = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/me", nil))
+	if want := []string{"literal"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("/users/me ran %v, want %v", got, want)
+	}
+
+	got = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	if want := []string{"param"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("/users/42 ran %v, want %v", got, want)
+	}
+}
+
+func TestParamBinding(t *testing.T) {
+	var gotID, gotMissing string
+	r := New()
+	r.Get("/users/:id", chain.HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+			gotID = Param(ctx, "id")
+			gotMissing = Param(ctx, "missing")
+		},
+	))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	if gotID != "42" {
+		t.Fatalf("Param(ctx, \"id\") = %q, want %q", gotID, "42")
+	}
+	if gotMissing != "" {
+		t.Fatalf("Param(ctx, \"missing\") = %q, want empty", gotMissing)
+	}
+}
+
+func TestUseAndGroupDoNotLeakToSiblings(t *testing.T) {
+	var got []string
+	r := New()
+	r.Use(markMW("global", &got))
+	r.Group(func(r *Router) {
+		r.Use(markMW("group", &got))
+		r.Get("/a", markHandler("a", &got))
+	})
+	r.Get("/b", markHandler("b", &got))
+
+	got = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil))
+	if want := []string{"global", "group", "a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("/a ran %v, want %v", got, want)
+	}
+
+	got = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/b", nil))
+	if want := []string{"global", "b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("/b ran %v, want %v (group middleware leaked into a sibling route)", got, want)
+	}
+}
+
+func TestMountMatchesWholeSubtreeAndLeavesURLUnmutated(t *testing.T) {
+	sub := http.NewServeMux()
+	sub.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path))
+	})
+
+	r := New()
+	r.Mount("/api", sub)
+
+	for _, path := range []string{"/api", "/api/users/5"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		originalPath := req.URL.Path
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		body, _ := ioutil.ReadAll(rec.Result().Body)
+		wantBody := path[len("/api"):]
+		if wantBody == "" {
+			wantBody = "/"
+		}
+		if string(body) != wantBody {
+			t.Fatalf("mounted handler for %s saw path %q, want %q", path, body, wantBody)
+		}
+
+		if req.URL.Path != originalPath {
+			t.Fatalf("Mount mutated the original request's URL.Path: got %q, want %q", req.URL.Path, originalPath)
+		}
+	}
+}
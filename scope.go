@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// Scope is a per-request key/value store attached to the context by
+// ScopeHandler. Unlike context.WithValue, which requires deriving and
+// propagating a new context for every value set, Scope can be mutated in
+// place, which fits middleware patterns that accumulate small values across
+// several wraps, such as request IDs, timing, or structured log fields.
+type Scope map[interface{}]interface{}
+
+// Get returns the value stored in the Scope for key, and whether it was
+// present.
+func (s Scope) Get(key interface{}) (interface{}, bool) {
+	v, ok := s[key]
+	return v, ok
+}
+
+// Set stores value in the Scope under key.
+func (s Scope) Set(key, value interface{}) {
+	s[key] = value
+}
+
+// Delete removes key from the Scope, if present.
+func (s Scope) Delete(key interface{}) {
+	delete(s, key)
+}
+
+type scopeCtxKey int
+
+const scopeKey scopeCtxKey = 0
+
+// FromContext returns the Scope attached to ctx by ScopeHandler, and whether
+// one was found.
+func FromContext(ctx context.Context) (Scope, bool) {
+	s, ok := ctx.Value(scopeKey).(Scope)
+	return s, ok
+}
+
+// ScopeHandler returns a Handler wrap that attaches a Scope with the given
+// capacity hint to the context seen by the downstream Handler, retrievable
+// via FromContext. If a Scope is already present in the context (e.g. this
+// wrap is nested inside another ScopeHandler further up the Chain) it is
+// reused rather than shadowed, so middlewares added anywhere in the Chain
+// share the same Scope.
+func ScopeHandler(cap int) func(Handler) Handler {
+	return func(h Handler) Handler {
+		return HandlerFunc(
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+				if _, ok := FromContext(ctx); !ok {
+					ctx = context.WithValue(ctx, scopeKey, make(Scope, cap))
+				}
+				h.ServeHTTPContext(ctx, w, r)
+			},
+		)
+	}
+}
+
+// WithScope appends a ScopeHandler(cap) wrap to the Chain and returns the new
+// Chain.
+func (c Chain) WithScope(cap int) Chain {
+	return c.Append(ScopeHandler(cap))
+}
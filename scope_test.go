@@ -0,0 +1,76 @@
+package chain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestScopeGetSetDelete(t *testing.T) {
+	s := make(Scope)
+
+	if _, ok := s.Get("k"); ok {
+		t.Fatal("Get on an empty Scope reported a value present")
+	}
+
+	s.Set("k", "v")
+	if v, ok := s.Get("k"); !ok || v != "v" {
+		t.Fatalf("Get(\"k\") = (%v, %v), want (\"v\", true)", v, ok)
+	}
+
+	s.Delete("k")
+	if _, ok := s.Get("k"); ok {
+		t.Fatal("value still present after Delete")
+	}
+}
+
+func TestScopeHandlerReusesScopeWhenNested(t *testing.T) {
+	var innerScope Scope
+
+	inner := ScopeHandler(5)(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			s, ok := FromContext(ctx)
+			if !ok {
+				t.Fatal("no Scope found in the nested ScopeHandler's context")
+			}
+			innerScope = s
+		},
+	))
+
+	outer := ScopeHandler(10)(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			s, ok := FromContext(ctx)
+			if !ok {
+				t.Fatal("no Scope found in the outer ScopeHandler's context")
+			}
+			s.Set("k", "v")
+			inner.ServeHTTPContext(ctx, w, r)
+		},
+	))
+
+	outer.ServeHTTPContext(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	v, ok := innerScope.Get("k")
+	if !ok || v != "v" {
+		t.Fatal("nested ScopeHandler created a new Scope instead of reusing the outer one")
+	}
+}
+
+func TestChainWithScope(t *testing.T) {
+	var got Scope
+	c := New(context.Background()).WithScope(0)
+
+	final := HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			got, _ = FromContext(ctx)
+		},
+	)
+
+	c.End(final).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got == nil {
+		t.Fatal("Chain.WithScope did not attach a Scope to the context")
+	}
+}
@@ -0,0 +1,527 @@
+package chain
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// WrapWriter is implemented by http.ResponseWriter wraps that need to stay
+// transparent to callers further down the Chain that type-assert the
+// http.ResponseWriter they receive for http.Flusher, http.Hijacker,
+// http.CloseNotifier or io.ReaderFrom. WrappedWriter returns the writer that
+// was wrapped, letting Unwrap walk back through a stack of wraps.
+type WrapWriter interface {
+	http.ResponseWriter
+	WrappedWriter() http.ResponseWriter
+}
+
+// StatusWriter is a WrapWriter that additionally tracks the status code and
+// byte count written through it, as produced by ResponseRecorder.
+type StatusWriter interface {
+	WrapWriter
+	Status() int
+	Size() int
+}
+
+// basicWriter is the base implementation wrapped by WrapResponseWriter. The
+// writerXXX types below embed it and add exactly the optional interfaces
+// (http.Flusher, http.Hijacker, http.CloseNotifier, io.ReaderFrom) that the
+// underlying http.ResponseWriter supports, so a type assertion against the
+// wrap behaves the same as one against the original writer.
+type basicWriter struct {
+	http.ResponseWriter
+	code        int
+	bytes       int
+	wroteHeader bool
+}
+
+func (b *basicWriter) WriteHeader(code int) {
+	if !b.wroteHeader {
+		b.code = code
+		b.wroteHeader = true
+	}
+	b.ResponseWriter.WriteHeader(code)
+}
+
+func (b *basicWriter) Write(buf []byte) (int, error) {
+	if !b.wroteHeader {
+		b.WriteHeader(http.StatusOK)
+	}
+	n, err := b.ResponseWriter.Write(buf)
+	b.bytes += n
+	return n, err
+}
+
+func (b *basicWriter) WrappedWriter() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+func (b *basicWriter) Status() int {
+	return b.code
+}
+
+func (b *basicWriter) Size() int {
+	return b.bytes
+}
+
+type writerF struct {
+	*basicWriter
+}
+
+func (w *writerF) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+type writerH struct {
+	*basicWriter
+}
+
+func (w *writerH) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+type writerC struct {
+	*basicWriter
+}
+
+func (w *writerC) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+type writerR struct {
+	*basicWriter
+}
+
+func (w *writerR) ReadFrom(src io.Reader) (int64, error) {
+	return w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+type writerFH struct {
+	*basicWriter
+}
+
+func (w *writerFH) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *writerFH) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+type writerFC struct {
+	*basicWriter
+}
+
+func (w *writerFC) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *writerFC) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+type writerFR struct {
+	*basicWriter
+}
+
+func (w *writerFR) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *writerFR) ReadFrom(src io.Reader) (int64, error) {
+	return w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+type writerHC struct {
+	*basicWriter
+}
+
+func (w *writerHC) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *writerHC) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+type writerHR struct {
+	*basicWriter
+}
+
+func (w *writerHR) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *writerHR) ReadFrom(src io.Reader) (int64, error) {
+	return w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+type writerCR struct {
+	*basicWriter
+}
+
+func (w *writerCR) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *writerCR) ReadFrom(src io.Reader) (int64, error) {
+	return w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+type writerFHC struct {
+	*basicWriter
+}
+
+func (w *writerFHC) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *writerFHC) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *writerFHC) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+type writerFHR struct {
+	*basicWriter
+}
+
+func (w *writerFHR) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *writerFHR) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *writerFHR) ReadFrom(src io.Reader) (int64, error) {
+	return w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+type writerFCR struct {
+	*basicWriter
+}
+
+func (w *writerFCR) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *writerFCR) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *writerFCR) ReadFrom(src io.Reader) (int64, error) {
+	return w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+type writerHCR struct {
+	*basicWriter
+}
+
+func (w *writerHCR) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *writerHCR) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *writerHCR) ReadFrom(src io.Reader) (int64, error) {
+	return w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+type writerFHCR struct {
+	*basicWriter
+}
+
+func (w *writerFHCR) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *writerFHCR) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *writerFHCR) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *writerFHCR) ReadFrom(src io.Reader) (int64, error) {
+	return w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+func newWrapWriter(w http.ResponseWriter) WrapWriter {
+	bw := &basicWriter{ResponseWriter: w}
+
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+	_, isCloseNotifier := w.(http.CloseNotifier)
+	_, isReaderFrom := w.(io.ReaderFrom)
+
+	switch {
+	case isFlusher && !isHijacker && !isCloseNotifier && !isReaderFrom:
+		return &writerF{bw}
+	case !isFlusher && isHijacker && !isCloseNotifier && !isReaderFrom:
+		return &writerH{bw}
+	case !isFlusher && !isHijacker && isCloseNotifier && !isReaderFrom:
+		return &writerC{bw}
+	case !isFlusher && !isHijacker && !isCloseNotifier && isReaderFrom:
+		return &writerR{bw}
+	case isFlusher && isHijacker && !isCloseNotifier && !isReaderFrom:
+		return &writerFH{bw}
+	case isFlusher && !isHijacker && isCloseNotifier && !isReaderFrom:
+		return &writerFC{bw}
+	case isFlusher && !isHijacker && !isCloseNotifier && isReaderFrom:
+		return &writerFR{bw}
+	case !isFlusher && isHijacker && isCloseNotifier && !isReaderFrom:
+		return &writerHC{bw}
+	case !isFlusher && isHijacker && !isCloseNotifier && isReaderFrom:
+		return &writerHR{bw}
+	case !isFlusher && !isHijacker && isCloseNotifier && isReaderFrom:
+		return &writerCR{bw}
+	case isFlusher && isHijacker && isCloseNotifier && !isReaderFrom:
+		return &writerFHC{bw}
+	case isFlusher && isHijacker && !isCloseNotifier && isReaderFrom:
+		return &writerFHR{bw}
+	case isFlusher && !isHijacker && isCloseNotifier && isReaderFrom:
+		return &writerFCR{bw}
+	case !isFlusher && isHijacker && isCloseNotifier && isReaderFrom:
+		return &writerHCR{bw}
+	case isFlusher && isHijacker && isCloseNotifier && isReaderFrom:
+		return &writerFHCR{bw}
+	default:
+		return bw
+	}
+}
+
+// WrapResponseWriter wraps w in one of several StatusWriter implementations,
+// selected dynamically so that the result implements exactly the optional
+// interfaces (http.Flusher, http.Hijacker, http.CloseNotifier, io.ReaderFrom)
+// that w itself implements.
+func WrapResponseWriter(w http.ResponseWriter) StatusWriter {
+	return newWrapWriter(w).(StatusWriter)
+}
+
+// Unwrap walks a (possibly nested) stack of WrapWriters starting at w,
+// returning the first one for which pred reports true. It returns false if
+// no writer in the stack, wrapped or not, satisfies pred.
+func Unwrap(w http.ResponseWriter, pred func(http.ResponseWriter) bool) (http.ResponseWriter, bool) {
+	for {
+		if pred(w) {
+			return w, true
+		}
+
+		ww, ok := w.(WrapWriter)
+		if !ok {
+			return nil, false
+		}
+		w = ww.WrappedWriter()
+	}
+}
+
+// ResponseRecorder is a Handler wrap that substitutes the http.ResponseWriter
+// passed downstream with one wrapped by WrapResponseWriter, so that
+// middleware placed after it in the Chain, which receives the wrapped
+// writer, can recover the status code and byte count of the response once
+// the Handler it wraps returns, e.g. for access logging. Wraps placed before
+// it keep the original writer and cannot see the recorded values. It does no
+// logging itself; pair it with a later wrap that calls Unwrap (or a direct
+// type assertion, since ServeHTTPContext runs inline) to read the recorded
+// values after the request completes.
+func ResponseRecorder(h Handler) Handler {
+	return HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTPContext(ctx, WrapResponseWriter(w), r)
+		},
+	)
+}
+
+// bufferedWriter buffers a response body in memory instead of writing it
+// through immediately, so the Handler wrap below can inspect or rewrite it
+// once the downstream Handler has produced the whole thing. Like basicWriter,
+// it is embedded by a set of writerXXX-style types below so the writer
+// handed downstream keeps exactly the optional interfaces (besides
+// http.Flusher, which buffering makes meaningless until the body is
+// flushed) that the real http.ResponseWriter supports.
+type bufferedWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+}
+
+func (b *bufferedWriter) WriteHeader(code int) {
+	if !b.wroteHeader {
+		b.code = code
+		b.wroteHeader = true
+	}
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if !b.wroteHeader {
+		b.WriteHeader(http.StatusOK)
+	}
+	return b.buf.Write(p)
+}
+
+func (b *bufferedWriter) WrappedWriter() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+// flushTo writes the buffered status code and body to w. It is promoted by
+// every bufferedWriterXXX type below, so BufferedWriter can call it without
+// caring which one newBufferedWriter picked.
+func (b *bufferedWriter) flushTo(w http.ResponseWriter) {
+	if b.wroteHeader {
+		w.WriteHeader(b.code)
+	}
+	b.buf.WriteTo(w)
+}
+
+type flushableBufferedWriter interface {
+	http.ResponseWriter
+	flushTo(w http.ResponseWriter)
+}
+
+type bufferedWriterH struct {
+	*bufferedWriter
+}
+
+func (w *bufferedWriterH) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+type bufferedWriterC struct {
+	*bufferedWriter
+}
+
+func (w *bufferedWriterC) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+type bufferedWriterR struct {
+	*bufferedWriter
+}
+
+func (w *bufferedWriterR) ReadFrom(src io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.buf.ReadFrom(src)
+}
+
+type bufferedWriterHC struct {
+	*bufferedWriter
+}
+
+func (w *bufferedWriterHC) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *bufferedWriterHC) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+type bufferedWriterHR struct {
+	*bufferedWriter
+}
+
+func (w *bufferedWriterHR) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *bufferedWriterHR) ReadFrom(src io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.buf.ReadFrom(src)
+}
+
+type bufferedWriterCR struct {
+	*bufferedWriter
+}
+
+func (w *bufferedWriterCR) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *bufferedWriterCR) ReadFrom(src io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.buf.ReadFrom(src)
+}
+
+type bufferedWriterHCR struct {
+	*bufferedWriter
+}
+
+func (w *bufferedWriterHCR) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *bufferedWriterHCR) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *bufferedWriterHCR) ReadFrom(src io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.buf.ReadFrom(src)
+}
+
+// newBufferedWriter wraps w in one of the bufferedWriterXXX types above,
+// selected dynamically like newWrapWriter, so the result keeps w's
+// http.Hijacker, http.CloseNotifier and io.ReaderFrom support (ReadFrom
+// writes into the buffer rather than through to w, to stay consistent with
+// Write). http.Flusher is deliberately never forwarded: flushing early would
+// defeat the purpose of buffering the body for whatever wraps this one.
+func newBufferedWriter(w http.ResponseWriter) flushableBufferedWriter {
+	bw := &bufferedWriter{ResponseWriter: w}
+
+	_, isHijacker := w.(http.Hijacker)
+	_, isCloseNotifier := w.(http.CloseNotifier)
+	_, isReaderFrom := w.(io.ReaderFrom)
+
+	switch {
+	case isHijacker && !isCloseNotifier && !isReaderFrom:
+		return &bufferedWriterH{bw}
+	case !isHijacker && isCloseNotifier && !isReaderFrom:
+		return &bufferedWriterC{bw}
+	case !isHijacker && !isCloseNotifier && isReaderFrom:
+		return &bufferedWriterR{bw}
+	case isHijacker && isCloseNotifier && !isReaderFrom:
+		return &bufferedWriterHC{bw}
+	case isHijacker && !isCloseNotifier && isReaderFrom:
+		return &bufferedWriterHR{bw}
+	case !isHijacker && isCloseNotifier && isReaderFrom:
+		return &bufferedWriterCR{bw}
+	case isHijacker && isCloseNotifier && isReaderFrom:
+		return &bufferedWriterHCR{bw}
+	default:
+		return bw
+	}
+}
+
+// BufferedWriter is a Handler wrap that buffers the downstream Handler's
+// entire response body in memory, flushing the status code and body to the
+// real http.ResponseWriter only once the Handler returns. This lets a wrap
+// further up the Chain (e.g. one computing a checksum or gzipping the body)
+// see and modify the complete response rather than a stream of writes.
+func BufferedWriter(h Handler) Handler {
+	return HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			bw := newBufferedWriter(w)
+			h.ServeHTTPContext(ctx, bw, r)
+			bw.flushTo(w)
+		},
+	)
+}
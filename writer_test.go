@@ -0,0 +1,173 @@
+package chain
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// fullResponseWriter implements http.ResponseWriter plus all four optional
+// interfaces WrapResponseWriter/BufferedWriter know how to preserve.
+type fullResponseWriter struct {
+	*httptest.ResponseRecorder
+	flushed  bool
+	hijacked bool
+	notifyCh chan bool
+}
+
+func newFullResponseWriter() *fullResponseWriter {
+	return &fullResponseWriter{ResponseRecorder: httptest.NewRecorder(), notifyCh: make(chan bool, 1)}
+}
+
+func (w *fullResponseWriter) Flush() { w.flushed = true }
+
+func (w *fullResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	server, _ := net.Pipe()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func (w *fullResponseWriter) CloseNotify() <-chan bool {
+	return w.notifyCh
+}
+
+func (w *fullResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	b, err := ioutil.ReadAll(src)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.ResponseRecorder.Write(b)
+	return int64(n), err
+}
+
+// bareResponseWriter implements only http.ResponseWriter, none of the
+// optional interfaces.
+type bareResponseWriter struct {
+	header http.Header
+	body   []byte
+	code   int
+}
+
+func (w *bareResponseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *bareResponseWriter) Write(b []byte) (int, error) {
+	w.body = append(w.body, b...)
+	return len(b), nil
+}
+
+func (w *bareResponseWriter) WriteHeader(code int) {
+	w.code = code
+}
+
+func TestWrapResponseWriterPreservesAllOptionalInterfaces(t *testing.T) {
+	full := newFullResponseWriter()
+	wrapped := WrapResponseWriter(full)
+
+	flusher, ok := wrapped.(http.Flusher)
+	if !ok {
+		t.Fatal("wrapped writer does not implement http.Flusher")
+	}
+	flusher.Flush()
+	if !full.flushed {
+		t.Fatal("Flush did not reach the underlying writer")
+	}
+
+	hijacker, ok := wrapped.(http.Hijacker)
+	if !ok {
+		t.Fatal("wrapped writer does not implement http.Hijacker")
+	}
+	if _, _, err := hijacker.Hijack(); err != nil {
+		t.Fatalf("Hijack returned an error: %v", err)
+	}
+	if !full.hijacked {
+		t.Fatal("Hijack did not reach the underlying writer")
+	}
+
+	notifier, ok := wrapped.(http.CloseNotifier)
+	if !ok {
+		t.Fatal("wrapped writer does not implement http.CloseNotifier")
+	}
+	if notifier.CloseNotify() != (<-chan bool)(full.notifyCh) {
+		t.Fatal("CloseNotify did not return the underlying writer's channel")
+	}
+
+	readerFrom, ok := wrapped.(io.ReaderFrom)
+	if !ok {
+		t.Fatal("wrapped writer does not implement io.ReaderFrom")
+	}
+	n, err := readerFrom.ReadFrom(strings.NewReader("hello"))
+	if err != nil || n != 5 {
+		t.Fatalf("ReadFrom(%q) = (%d, %v), want (5, nil)", "hello", n, err)
+	}
+	if full.ResponseRecorder.Body.String() != "hello" {
+		t.Fatalf("ReadFrom did not reach the underlying writer: body = %q", full.ResponseRecorder.Body.String())
+	}
+
+	if wrapped.Status() != 0 {
+		t.Fatalf("Status() = %d before any WriteHeader call, want 0", wrapped.Status())
+	}
+}
+
+func TestWrapResponseWriterOmitsUnsupportedOptionalInterfaces(t *testing.T) {
+	wrapped := WrapResponseWriter(&bareResponseWriter{})
+
+	if _, ok := wrapped.(http.Flusher); ok {
+		t.Fatal("wrapped writer implements http.Flusher even though the underlying writer does not")
+	}
+	if _, ok := wrapped.(http.Hijacker); ok {
+		t.Fatal("wrapped writer implements http.Hijacker even though the underlying writer does not")
+	}
+	if _, ok := wrapped.(http.CloseNotifier); ok {
+		t.Fatal("wrapped writer implements http.CloseNotifier even though the underlying writer does not")
+	}
+	if _, ok := wrapped.(io.ReaderFrom); ok {
+		t.Fatal("wrapped writer implements io.ReaderFrom even though the underlying writer does not")
+	}
+}
+
+func TestBufferedWriterPreservesHijackerCloseNotifierReaderFromButNotFlusher(t *testing.T) {
+	full := newFullResponseWriter()
+
+	var captured http.ResponseWriter
+	h := BufferedWriter(HandlerFunc(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			captured = w
+			io.WriteString(w, "buffered")
+
+			if full.ResponseRecorder.Body.Len() != 0 {
+				t.Fatal("BufferedWriter wrote through to the underlying writer before the Handler returned")
+			}
+		},
+	))
+
+	h.ServeHTTPContext(context.Background(), full, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if full.ResponseRecorder.Body.String() != "buffered" {
+		t.Fatalf("body after Handler returned = %q, want %q", full.ResponseRecorder.Body.String(), "buffered")
+	}
+
+	if _, ok := captured.(http.Flusher); ok {
+		t.Fatal("BufferedWriter's writer implements http.Flusher; Flush should be suppressed while buffering")
+	}
+	if _, ok := captured.(http.Hijacker); !ok {
+		t.Fatal("BufferedWriter's writer does not implement http.Hijacker")
+	}
+	if _, ok := captured.(http.CloseNotifier); !ok {
+		t.Fatal("BufferedWriter's writer does not implement http.CloseNotifier")
+	}
+	if _, ok := captured.(io.ReaderFrom); !ok {
+		t.Fatal("BufferedWriter's writer does not implement io.ReaderFrom")
+	}
+}